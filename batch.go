@@ -0,0 +1,127 @@
+// Copyright 2013 Bruno Albuquerque (bga@bug-br.org.br).
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package deluge
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// batchCall is a single queued RPC call awaiting Batch.Do.
+type batchCall struct {
+	method string
+	params []interface{}
+}
+
+// Batch accumulates RPC calls to run concurrently against a bounded worker
+// pool, so callers needing e.g. the status of hundreds of torrents don't
+// pay one request latency per torrent. Deluge's JSON endpoint has no
+// native batch call, so this fans out real RPCs instead of building a
+// single combined request.
+type Batch struct {
+	d     *Deluge
+	calls []batchCall
+}
+
+// Batch starts a new batch of calls against d.
+func (d *Deluge) Batch() *Batch {
+	return &Batch{d: d}
+}
+
+// Add queues method/params to be run when Do is called. It returns b so
+// calls can be chained.
+func (b *Batch) Add(method string, params ...interface{}) *Batch {
+	b.calls = append(b.calls, batchCall{method, params})
+	return b
+}
+
+// Do runs every queued call, using up to the Deluge instance's configured
+// batch concurrency (see WithBatchConcurrency, default 8) workers at a
+// time. Results are returned in the same order calls were added. If ctx is
+// canceled, in-flight and not-yet-started calls fail with ctx.Err(). If
+// any call fails, Do returns a non-nil *BatchError alongside the partial
+// results; results[i] is the zero value for any index that failed.
+func (b *Batch) Do(ctx context.Context) ([]interface{}, error) {
+	results := make([]interface{}, len(b.calls))
+	errs := make([]error, len(b.calls))
+
+	concurrency := b.d.opts.batchConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, call := range b.calls {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, call batchCall) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			result, err := b.d.transport.Call(ctx, call.method, call.params)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			results[i] = result
+		}(i, call)
+	}
+
+	wg.Wait()
+
+	failed := map[int]error{}
+	for i, err := range errs {
+		if err != nil {
+			failed[i] = err
+		}
+	}
+	if len(failed) > 0 {
+		return results, &BatchError{Failed: failed}
+	}
+
+	return results, nil
+}
+
+// BatchError reports which sub-calls of a Batch failed, keyed by their
+// index in the batch.
+type BatchError struct {
+	Failed map[int]error
+}
+
+func (e *BatchError) Error() string {
+	indexes := make([]int, 0, len(e.Failed))
+	for i := range e.Failed {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	parts := make([]string, len(indexes))
+	for n, i := range indexes {
+		parts[n] = fmt.Sprintf("call %d: %v", i, e.Failed[i])
+	}
+
+	return fmt.Sprintf("deluge: %d batch call(s) failed: %s", len(e.Failed), strings.Join(parts, "; "))
+}
@@ -0,0 +1,426 @@
+// Copyright 2013 Bruno Albuquerque (bga@bug-br.org.br).
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package deluge
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Hidendra/go-deluge/rencode"
+)
+
+// reconnectDelay is how long supervise waits before redialing after the
+// connection drops.
+const reconnectDelay = time.Second
+
+const (
+	rpcResponse = 1
+	rpcError    = 2
+	rpcEvent    = 3
+
+	clientVersion = "go-deluge"
+)
+
+// pendingCall is the bookkeeping kept for a single in-flight request on a
+// DaemonTransport connection.
+type pendingCall struct {
+	result chan interface{}
+	err    chan error
+}
+
+// DaemonTransport talks directly to a deluged daemon process using its
+// native rencode-over-TLS protocol, without requiring deluge-web to be
+// running.
+type DaemonTransport struct {
+	host string
+	port int
+
+	login    string
+	password string
+
+	opts *options
+
+	conn   *tls.Conn
+	reader *bufio.Reader
+
+	id uint64
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[uint64]*pendingCall
+	closing bool
+	closed  chan struct{}
+}
+
+// NewDaemonTransport connects to the deluged daemon at host:port over TLS
+// and authenticates with login/password via daemon.login.
+func NewDaemonTransport(host string, port int, login, password string, opts *options) (*DaemonTransport, error) {
+	t := &DaemonTransport{
+		host:     host,
+		port:     port,
+		login:    login,
+		password: password,
+		opts:     opts,
+		pending:  make(map[uint64]*pendingCall),
+		closed:   make(chan struct{}),
+	}
+
+	if err := t.dial(); err != nil {
+		return nil, err
+	}
+
+	if err := t.authenticate(); err != nil {
+		t.Close()
+		return nil, err
+	}
+
+	go t.supervise()
+
+	return t, nil
+}
+
+// dial opens a fresh TLS connection to the daemon, replacing any prior
+// connection/reader. It is used both for the initial connect and, from
+// supervise, to redial after a disconnect.
+func (t *DaemonTransport) dial() error {
+	conn, err := tls.Dial("tcp", fmt.Sprintf("%s:%d", t.host, t.port), &tls.Config{
+		InsecureSkipVerify: t.opts.insecureSkipVerify,
+	})
+	if err != nil {
+		return fmt.Errorf("dialing deluge daemon: %w", err)
+	}
+
+	t.writeMu.Lock()
+	t.conn = conn
+	t.reader = bufio.NewReader(conn)
+	t.writeMu.Unlock()
+
+	return nil
+}
+
+// authenticate sends daemon.login and waits for its response directly,
+// without going through the pending-call dispatch readLoop uses, since it
+// runs before (or between) readLoop's lifetime on a connection and is the
+// only goroutine reading from it at the time.
+func (t *DaemonTransport) authenticate() error {
+	requestID := atomic.AddUint64(&t.id, 1)
+
+	request := []interface{}{
+		[]interface{}{requestID, "daemon.login", []interface{}{t.login, t.password, clientVersion}, map[string]interface{}{}},
+	}
+
+	if err := t.send(request); err != nil {
+		return err
+	}
+
+	payload, err := t.readMessage()
+	if err != nil {
+		return err
+	}
+
+	value, _, err := rencode.Decode(payload)
+	if err != nil {
+		return err
+	}
+
+	message, ok := value.([]interface{})
+	if !ok || len(message) < 2 {
+		return fmt.Errorf("deluge daemon: malformed login response")
+	}
+
+	if responseID, ok := asUint64(message[1]); !ok || responseID != requestID {
+		return fmt.Errorf("deluge daemon: unexpected response to login")
+	}
+
+	messageType, _ := message[0].(int64)
+	if messageType == rpcError {
+		var detail interface{}
+		if len(message) > 2 {
+			detail = message[2]
+		}
+		return fmt.Errorf("deluge daemon error: %v", detail)
+	}
+
+	return nil
+}
+
+// supervise owns the connection for the transport's lifetime. It services
+// the current connection's readLoop and, whenever that returns because the
+// connection dropped rather than because Close was called, redials and
+// re-authenticates before resuming — the daemon transport's reconnect loop.
+// In-flight calls at the time of a drop are failed immediately rather than
+// left to time out.
+func (t *DaemonTransport) supervise() {
+	for {
+		t.readLoop()
+		t.failPending(fmt.Errorf("deluge daemon connection lost"))
+
+		if t.isClosing() {
+			close(t.closed)
+			return
+		}
+
+		for {
+			if t.isClosing() {
+				close(t.closed)
+				return
+			}
+			if err := t.dial(); err == nil {
+				if err := t.authenticate(); err == nil {
+					break
+				}
+			}
+			time.Sleep(reconnectDelay)
+		}
+	}
+}
+
+func (t *DaemonTransport) isClosing() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.closing
+}
+
+// failPending fails every call currently awaiting a response, e.g. because
+// the connection carrying it just dropped.
+func (t *DaemonTransport) failPending(err error) {
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = make(map[uint64]*pendingCall)
+	t.mu.Unlock()
+
+	for _, call := range pending {
+		call.err <- err
+	}
+}
+
+// Call implements Transport.
+func (t *DaemonTransport) Call(ctx context.Context, method string, params []interface{}) (interface{}, error) {
+	requestID := atomic.AddUint64(&t.id, 1)
+
+	call := &pendingCall{
+		result: make(chan interface{}, 1),
+		err:    make(chan error, 1),
+	}
+
+	t.mu.Lock()
+	t.pending[requestID] = call
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, requestID)
+		t.mu.Unlock()
+	}()
+
+	request := []interface{}{
+		[]interface{}{requestID, method, toInterfaceSlice(params), map[string]interface{}{}},
+	}
+
+	if err := t.send(request); err != nil {
+		return nil, err
+	}
+
+	select {
+	case result := <-call.result:
+		return result, nil
+	case err := <-call.err:
+		return nil, err
+	case <-t.closed:
+		return nil, fmt.Errorf("deluge daemon connection closed")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// send writes a single message to the connection. The header (when present)
+// and the compressed payload are written under writeMu so that concurrent
+// Call goroutines can never interleave their writes and corrupt the wire
+// framing.
+func (t *DaemonTransport) send(request []interface{}) error {
+	encoded, err := rencode.Encode(request)
+	if err != nil {
+		return err
+	}
+
+	var compressed bytes.Buffer
+	w := zlib.NewWriter(&compressed)
+	if _, err := w.Write(encoded); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	conn := t.conn
+
+	if t.opts.protocolVersion == 0 {
+		_, err = conn.Write(compressed.Bytes())
+		return err
+	}
+
+	header := make([]byte, 5)
+	header[0] = 1
+	binary.BigEndian.PutUint32(header[1:], uint32(compressed.Len()))
+
+	buf := append(header, compressed.Bytes()...)
+	_, err = conn.Write(buf)
+
+	return err
+}
+
+// readLoop decodes framed messages off the connection and dispatches them
+// to the pending call they answer, until the connection drops or is
+// closed; supervise decides what happens next.
+func (t *DaemonTransport) readLoop() {
+	for {
+		payload, err := t.readMessage()
+		if err != nil {
+			return
+		}
+
+		value, _, err := rencode.Decode(payload)
+		if err != nil {
+			continue
+		}
+
+		message, ok := value.([]interface{})
+		if !ok || len(message) < 2 {
+			continue
+		}
+
+		messageType, ok := message[0].(int64)
+		if !ok {
+			continue
+		}
+
+		requestID, ok := asUint64(message[1])
+		if !ok {
+			continue
+		}
+
+		t.mu.Lock()
+		call, ok := t.pending[requestID]
+		t.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		switch messageType {
+		case rpcResponse:
+			var result interface{}
+			if len(message) > 2 {
+				result = message[2]
+			}
+			call.result <- result
+		case rpcError:
+			var detail interface{}
+			if len(message) > 2 {
+				detail = message[2]
+			}
+			call.err <- fmt.Errorf("deluge daemon error: %v", detail)
+		default:
+			// Events are not request-scoped; ignore them here.
+		}
+	}
+}
+
+func (t *DaemonTransport) readMessage() ([]byte, error) {
+	if t.opts.protocolVersion == 0 {
+		// Protocol v0 has no length prefix; each message is simply a
+		// zlib stream back to back with the next. t.reader implements
+		// io.ByteReader, so the flate decompressor behind zlib.NewReader
+		// reads it one byte at a time and stops exactly at the stream's
+		// end marker instead of over-reading into the next message.
+		zr, err := zlib.NewReader(t.reader)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+
+		return ioutil.ReadAll(zr)
+	}
+
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(t.reader, header); err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[1:])
+	compressed := make([]byte, length)
+	if _, err := io.ReadFull(t.reader, compressed); err != nil {
+		return nil, err
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	return ioutil.ReadAll(zr)
+}
+
+// Close implements Transport. It stops the reconnect loop and tears down
+// the current connection.
+func (t *DaemonTransport) Close() error {
+	t.mu.Lock()
+	t.closing = true
+	t.mu.Unlock()
+
+	t.writeMu.Lock()
+	conn := t.conn
+	t.writeMu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+
+	return conn.Close()
+}
+
+func toInterfaceSlice(params []interface{}) []interface{} {
+	if params == nil {
+		return []interface{}{}
+	}
+
+	return params
+}
+
+func asUint64(value interface{}) (uint64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return uint64(v), true
+	case uint64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
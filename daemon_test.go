@@ -0,0 +1,110 @@
+// Copyright 2013 Bruno Albuquerque (bga@bug-br.org.br).
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package deluge
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/Hidendra/go-deluge/rencode"
+)
+
+func writeZlibBlock(t *testing.T, buf *bytes.Buffer, payload []byte) {
+	t.Helper()
+
+	w := zlib.NewWriter(buf)
+	if _, err := w.Write(payload); err != nil {
+		t.Fatalf("zlib write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("zlib close: %v", err)
+	}
+}
+
+// TestDaemonTransportReadMessageV0Framing verifies that two v0 (Deluge 1.x)
+// messages written back to back, with no length prefix between them, are
+// each decoded as a single message off the shared *bufio.Reader rather than
+// the first message's zlib stream over-reading into the second's.
+func TestDaemonTransportReadMessageV0Framing(t *testing.T) {
+	first, err := rencode.Encode([]interface{}{int64(1), "first"})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	second, err := rencode.Encode([]interface{}{int64(2), "second"})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	var buf bytes.Buffer
+	writeZlibBlock(t, &buf, first)
+	writeZlibBlock(t, &buf, second)
+
+	dt := &DaemonTransport{
+		opts:   &options{protocolVersion: 0},
+		reader: bufio.NewReader(&buf),
+	}
+
+	got1, err := dt.readMessage()
+	if err != nil {
+		t.Fatalf("readMessage (first): %v", err)
+	}
+	if !reflect.DeepEqual(got1, first) {
+		t.Fatalf("readMessage (first) = %v, want %v", got1, first)
+	}
+
+	got2, err := dt.readMessage()
+	if err != nil {
+		t.Fatalf("readMessage (second): %v", err)
+	}
+	if !reflect.DeepEqual(got2, second) {
+		t.Fatalf("readMessage (second) = %v, want %v", got2, second)
+	}
+}
+
+// TestDaemonTransportReadMessageV1Framing verifies the length-prefixed v1
+// (Deluge 2.x) framing used by readMessage.
+func TestDaemonTransportReadMessageV1Framing(t *testing.T) {
+	payload, err := rencode.Encode([]interface{}{int64(1), "hello"})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	var compressed bytes.Buffer
+	writeZlibBlock(t, &compressed, payload)
+
+	var buf bytes.Buffer
+	header := make([]byte, 5)
+	header[0] = 1
+	binary.BigEndian.PutUint32(header[1:], uint32(compressed.Len()))
+	buf.Write(header)
+	buf.Write(compressed.Bytes())
+
+	dt := &DaemonTransport{
+		opts:   &options{protocolVersion: 1},
+		reader: bufio.NewReader(&buf),
+	}
+
+	got, err := dt.readMessage()
+	if err != nil {
+		t.Fatalf("readMessage: %v", err)
+	}
+	if !reflect.DeepEqual(got, payload) {
+		t.Fatalf("readMessage = %v, want %v", got, payload)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2013 Bruno Albuquerque (bga@bug-br.org.br).
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package deluge
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// maxInteractions bounds the in-memory ring buffer kept by a debug-enabled
+// transport, so long-running processes don't leak memory recording every
+// call they ever made.
+const maxInteractions = 100
+
+// Interaction is a single recorded RPC call/response pair, in the spirit of
+// go-libdeluge's DebugSaveInteractions/DebugServerResponses.
+type Interaction struct {
+	Method     string
+	RequestID  uint64
+	Timestamp  time.Time
+	Request    json.RawMessage
+	Response   json.RawMessage
+	StatusCode int
+	Err        string
+}
+
+// debugger is implemented by transports that support recording their
+// interactions; currently only WebJSONTransport does.
+type debugger interface {
+	setDebugWriter(w io.Writer)
+	interactions() []Interaction
+}
+
+// SetDebugWriter enables interaction logging on d, writing a
+// newline-delimited JSON record for every outgoing request and its
+// response (including errors, non-200 statuses and JSON-error payloads) to
+// w. Pass nil to disable. Has no effect on transports that do not support
+// it (currently the daemon transport).
+func (d *Deluge) SetDebugWriter(w io.Writer) {
+	if dbg, ok := d.transport.(debugger); ok {
+		dbg.setDebugWriter(w)
+	}
+}
+
+// Interactions returns the most recent recorded call/response pairs (up to
+// maxInteractions), oldest first. Returns nil on transports that do not
+// support recording.
+func (d *Deluge) Interactions() []Interaction {
+	if dbg, ok := d.transport.(debugger); ok {
+		return dbg.interactions()
+	}
+
+	return nil
+}
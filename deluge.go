@@ -12,56 +12,72 @@
 // License for the specific language governing permissions and limitations under
 // the License.
 
-// Package deluge implements a Go wrapper around the Deluge Remote JSON API
-// (http://deluge-torrent.org/docs/1.2/core/rpc.html#remote-api). This allows
-// programmers to control Deluge (http://deluge-torrent.org) programatically
-// from inside Go programs. Note this is a work in progress and not everything
-// is implemented but adding extra RPC calls is trivial.
+// Package deluge implements a Go wrapper around the Deluge RPC API. Two
+// transports are available: the WebJSONTransport talks JSON-RPC to the
+// deluge-web process (http://deluge-torrent.org/docs/1.2/core/rpc.html#remote-api),
+// while the DaemonTransport talks the native rencode protocol directly to
+// deluged on port 58846, requiring no deluge-web process at all. Note this
+// is a work in progress and not everything is implemented but adding extra
+// RPC calls is trivial.
 package deluge
 
-import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
-	"net/http"
-	"sync/atomic"
-)
+import "context"
 
 // Deluge represents an endpoint for Deluge RPC requests.
 type Deluge struct {
-	url      string
-	password string
+	transport Transport
+	opts      *options
+}
 
-	client  *http.Client
-	cookies []*http.Cookie
+// New instantiates a new Deluge instance talking to the deluge-web JSON
+// endpoint at url and authenticates with password.
+func New(url, password string, opts ...Option) (*Deluge, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
 
-	id uint64
+	transport, err := NewWebJSONTransport(url, password, o)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Deluge{transport, o}, nil
 }
 
-// New instantiates a new Deluge instance and authenticates with the
-// server.
-func New(url, password string) (*Deluge, error) {
-	d := &Deluge{
-		url,
-		password,
-		new(http.Client),
-		nil,
-		0,
+// NewDaemon instantiates a new Deluge instance talking directly to a
+// deluged daemon at host:port (deluged listens on 58846 unless configured
+// otherwise; no default is applied to port here) over the native rencode
+// protocol, authenticating with login/password.
+func NewDaemon(host string, port int, login, password string, opts ...Option) (*Deluge, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
 	}
 
-	err := d.authLogin()
+	transport, err := NewDaemonTransport(host, port, login, password, o)
 	if err != nil {
 		return nil, err
 	}
 
-	return d, err
+	return &Deluge{transport, o}, nil
+}
+
+// Close releases any resources held by the underlying transport.
+func (d *Deluge) Close() error {
+	return d.transport.Close()
 }
 
 // CoreGetTorrentState wraps the core.get_torrent_state RPC call.
 // Returns a list of torrent ids in the session.
 func (d *Deluge) CoreGetTorrentState() ([]string, error) {
-	response, err := d.sendJsonRequest("core.get_session_state", []interface{}{})
+	return d.CoreGetTorrentStateContext(context.Background())
+}
+
+// CoreGetTorrentStateContext is CoreGetTorrentState with a caller-supplied
+// context for cancellation.
+func (d *Deluge) CoreGetTorrentStateContext(ctx context.Context) ([]string, error) {
+	response, err := d.sendJsonRequest(ctx, "core.get_session_state", []interface{}{})
 	if err != nil {
 		return nil, err
 	}
@@ -73,9 +89,15 @@ func (d *Deluge) CoreGetTorrentState() ([]string, error) {
 // torrentId is the info hash of the torrent to retrieve status for.
 // Returns the map returned by status for the torrent. TODO struct?
 func (d *Deluge) CoreGetTorrentStatus(torrentId string) (map[string]interface{}, error) {
+	return d.CoreGetTorrentStatusContext(context.Background(), torrentId)
+}
+
+// CoreGetTorrentStatusContext is CoreGetTorrentStatus with a
+// caller-supplied context for cancellation.
+func (d *Deluge) CoreGetTorrentStatusContext(ctx context.Context, torrentId string) (map[string]interface{}, error) {
 	keys := []string{}
 
-	response, err := d.sendJsonRequest("core.get_torrent_status", []interface{}{torrentId, keys})
+	response, err := d.sendJsonRequest(ctx, "core.get_torrent_status", []interface{}{torrentId, keys})
 	if err != nil {
 		return nil, err
 	}
@@ -87,10 +109,16 @@ func (d *Deluge) CoreGetTorrentStatus(torrentId string) (map[string]interface{},
 // It returns the status of all torrents in the session.
 // Returns the map returned by status for the torrent. TODO struct?
 func (d *Deluge) CoreGetTorrentsStatus() (map[string]interface{}, error) {
+	return d.CoreGetTorrentsStatusContext(context.Background())
+}
+
+// CoreGetTorrentsStatusContext is CoreGetTorrentsStatus with a
+// caller-supplied context for cancellation.
+func (d *Deluge) CoreGetTorrentsStatusContext(ctx context.Context) (map[string]interface{}, error) {
 	filter := map[string]interface{}{}
 	keys := []string{}
 
-	response, err := d.sendJsonRequest("core.get_torrents_status", []interface{}{filter, keys})
+	response, err := d.sendJsonRequest(ctx, "core.get_torrents_status", []interface{}{filter, keys})
 	if err != nil {
 		return nil, err
 	}
@@ -103,7 +131,13 @@ func (d *Deluge) CoreGetTorrentsStatus() (map[string]interface{}, error) {
 // the file and options is a map with options to be set (consult de Deluge
 // Torrent documentation for a list of valid options).
 func (d *Deluge) CoreAddTorrentFile(fileName, fileDump string, options map[string]interface{}) (string, error) {
-	response, err := d.sendJsonRequest("core.add_torrent_file", []interface{}{fileName, fileDump, options})
+	return d.CoreAddTorrentFileContext(context.Background(), fileName, fileDump, options)
+}
+
+// CoreAddTorrentFileContext is CoreAddTorrentFile with a caller-supplied
+// context for cancellation.
+func (d *Deluge) CoreAddTorrentFileContext(ctx context.Context, fileName, fileDump string, options map[string]interface{}) (string, error) {
+	response, err := d.sendJsonRequest(ctx, "core.add_torrent_file", []interface{}{fileName, fileDump, options})
 	if err != nil {
 		return "", err
 	}
@@ -115,7 +149,13 @@ func (d *Deluge) CoreAddTorrentFile(fileName, fileDump string, options map[strin
 // the Magnet URL for the torrent and options is a map with options to be set
 // (consult de Deluge Torrent documentation for a list of valid options).
 func (d *Deluge) CoreAddTorrentMagnet(magnetUrl string, options map[string]interface{}) (string, error) {
-	response, err := d.sendJsonRequest("core.add_torrent_magnet", []interface{}{magnetUrl, options})
+	return d.CoreAddTorrentMagnetContext(context.Background(), magnetUrl, options)
+}
+
+// CoreAddTorrentMagnetContext is CoreAddTorrentMagnet with a
+// caller-supplied context for cancellation.
+func (d *Deluge) CoreAddTorrentMagnetContext(ctx context.Context, magnetUrl string, options map[string]interface{}) (string, error) {
+	response, err := d.sendJsonRequest(ctx, "core.add_torrent_magnet", []interface{}{magnetUrl, options})
 	if err != nil {
 		return "", err
 	}
@@ -127,7 +167,13 @@ func (d *Deluge) CoreAddTorrentMagnet(magnetUrl string, options map[string]inter
 // the URL for the torrent and options is a map with options to be set
 // (consult de Deluge Torrent documentation for a list of valid options).
 func (d *Deluge) CoreAddTorrentUrl(torrentUrl string, options map[string]interface{}) (string, error) {
-	response, err := d.sendJsonRequest("core.add_torrent_url", []interface{}{torrentUrl, options})
+	return d.CoreAddTorrentUrlContext(context.Background(), torrentUrl, options)
+}
+
+// CoreAddTorrentUrlContext is CoreAddTorrentUrl with a caller-supplied
+// context for cancellation.
+func (d *Deluge) CoreAddTorrentUrlContext(ctx context.Context, torrentUrl string, options map[string]interface{}) (string, error) {
+	response, err := d.sendJsonRequest(ctx, "core.add_torrent_url", []interface{}{torrentUrl, options})
 	if err != nil {
 		return "", err
 	}
@@ -139,78 +185,28 @@ func (d *Deluge) CoreAddTorrentUrl(torrentUrl string, options map[string]interfa
 // the info hash for the torrent to remove and removeData is a boolean flag
 // for if the data attached to the torrent should be removed.
 func (d *Deluge) CoreRemoveTorrent(torrentId string, removeData bool) (bool, error) {
-	response, err := d.sendJsonRequest("core.remove_torrent", []interface{}{torrentId, removeData})
-	if err != nil {
-		return false, err
-	}
-
-	return response["result"].(bool), nil
+	return d.CoreRemoveTorrentContext(context.Background(), torrentId, removeData)
 }
 
-func (d *Deluge) authLogin() error {
-	response, err := d.sendJsonRequest("auth.login", []interface{}{d.password})
+// CoreRemoveTorrentContext is CoreRemoveTorrent with a caller-supplied
+// context for cancellation.
+func (d *Deluge) CoreRemoveTorrentContext(ctx context.Context, torrentId string, removeData bool) (bool, error) {
+	response, err := d.sendJsonRequest(ctx, "core.remove_torrent", []interface{}{torrentId, removeData})
 	if err != nil {
-		return err
-	}
-
-	if response["result"] != true {
-		return fmt.Errorf("authetication failed")
+		return false, err
 	}
 
-	return nil
+	return response["result"].(bool), nil
 }
 
-func (d *Deluge) sendJsonRequest(method string, params []interface{}) (map[string]interface{}, error) {
-	atomic.AddUint64(&(d.id), 1)
-	data, err := json.Marshal(map[string]interface{}{
-		"method": method,
-		"id":     d.id,
-		"params": params,
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequest("POST", d.url, bytes.NewReader(data))
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	if d.cookies != nil {
-		for _, cookie := range d.cookies {
-			req.AddCookie(cookie)
-		}
-	}
-
-	resp, err := d.client.Do(req)
+// sendJsonRequest routes method/params through the configured transport and
+// wraps the result the same way the old direct-HTTP implementation did, so
+// every Core* method above keeps working unchanged regardless of transport.
+func (d *Deluge) sendJsonRequest(ctx context.Context, method string, params []interface{}) (map[string]interface{}, error) {
+	result, err := d.transport.Call(ctx, method, params)
 	if err != nil {
 		return nil, err
 	}
 
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("received non-ok status to http request : %d", resp.StatusCode)
-	}
-
-	d.cookies = resp.Cookies()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	result := make(map[string]interface{})
-	err = json.Unmarshal(body, &result)
-	if err != nil {
-		return nil, err
-	}
-
-	if result["error"] != nil {
-		return nil, fmt.Errorf("json error : %v", result["error"])
-	}
-
-	return result, err
+	return map[string]interface{}{"result": result}, nil
 }
@@ -0,0 +1,103 @@
+// Copyright 2013 Bruno Albuquerque (bga@bug-br.org.br).
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package deluge_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	deluge "github.com/Hidendra/go-deluge"
+	"github.com/Hidendra/go-deluge/torrentclienttest"
+)
+
+const testTorrentID = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+
+// fakeDelugeWeb is a minimal stand-in for deluge-web's JSON-RPC endpoint,
+// just enough of it for torrentclienttest.Run to exercise *deluge.Deluge
+// end to end over real HTTP.
+type fakeDelugeWeb struct {
+	status  map[string]interface{}
+	deleted bool
+}
+
+func newFakeDelugeWeb() *fakeDelugeWeb {
+	return &fakeDelugeWeb{
+		status: map[string]interface{}{
+			"hash":  testTorrentID,
+			"name":  testTorrentID,
+			"state": "Downloading",
+			"files": []interface{}{},
+		},
+	}
+}
+
+func (f *fakeDelugeWeb) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Method string        `json:"method"`
+		ID     uint64        `json:"id"`
+		Params []interface{} `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var result, rpcErr interface{}
+
+	switch request.Method {
+	case "auth.login":
+		result = true
+	case "core.get_torrent_status":
+		if f.deleted {
+			rpcErr = "unknown torrent " + testTorrentID
+			break
+		}
+		result = f.status
+	case "label.set_torrent":
+		f.status["label"] = request.Params[1]
+	case "core.pause_torrent":
+		f.status["state"] = "Paused"
+	case "core.resume_torrent":
+		f.status["state"] = "Downloading"
+	case "core.remove_torrent":
+		f.deleted = true
+		result = true
+	default:
+		http.Error(w, "unknown method "+request.Method, http.StatusNotImplemented)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":     request.ID,
+		"result": result,
+		"error":  rpcErr,
+	})
+}
+
+func TestDelugeTorrentClientConformance(t *testing.T) {
+	server := httptest.NewServer(newFakeDelugeWeb())
+	defer server.Close()
+
+	client, err := deluge.New(server.URL, "password")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer client.Close()
+
+	torrentclienttest.Run(t, client, testTorrentID)
+}
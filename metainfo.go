@@ -0,0 +1,261 @@
+// Copyright 2013 Bruno Albuquerque (bga@bug-br.org.br).
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package deluge
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Metainfo is the subset of a .torrent file's fields callers typically need
+// to decide what to do with a torrent before submitting it to Deluge.
+type Metainfo struct {
+	InfoHash    string
+	DisplayName string
+	TotalSize   int64
+	Files       []FileInfo
+	Trackers    []string
+}
+
+// MagnetURI builds a magnet link for this torrent, following the same
+// xt/dn/tr layout used throughout the BitTorrent ecosystem (see e.g. the
+// anacrolix/torrent examples).
+func (m *Metainfo) MagnetURI() string {
+	uri := "magnet:?xt=urn:btih:" + m.InfoHash
+	if m.DisplayName != "" {
+		uri += "&dn=" + url.QueryEscape(m.DisplayName)
+	}
+	for _, tracker := range m.Trackers {
+		uri += "&tr=" + url.QueryEscape(tracker)
+	}
+
+	return uri
+}
+
+// ParseMetainfo bencode-decodes a .torrent file read from r and extracts
+// its info hash, display name, total size, file list and trackers.
+func ParseMetainfo(r io.Reader) (*Metainfo, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 || data[0] != 'd' {
+		return nil, fmt.Errorf("metainfo: not a bencoded dictionary")
+	}
+
+	pos := 1
+	var announce string
+	var announceList []interface{}
+	var info map[string]interface{}
+	var infoHash string
+
+	for pos < len(data) && data[pos] != 'e' {
+		key, newPos, err := decodeBencodeString(data, pos)
+		if err != nil {
+			return nil, err
+		}
+		pos = newPos
+
+		valueStart := pos
+		value, newPos, err := decodeBencodeValue(data, pos)
+		if err != nil {
+			return nil, err
+		}
+		pos = newPos
+
+		switch key {
+		case "info":
+			sum := sha1.Sum(data[valueStart:pos])
+			infoHash = hex.EncodeToString(sum[:])
+			info, _ = value.(map[string]interface{})
+		case "announce":
+			announce, _ = value.(string)
+		case "announce-list":
+			announceList, _ = value.([]interface{})
+		}
+	}
+
+	if info == nil {
+		return nil, fmt.Errorf("metainfo: missing info dictionary")
+	}
+
+	m := &Metainfo{
+		InfoHash: infoHash,
+	}
+
+	if name, ok := info["name"].(string); ok {
+		m.DisplayName = name
+	}
+
+	if length, ok := info["length"].(int64); ok {
+		m.TotalSize = length
+		m.Files = []FileInfo{{Path: m.DisplayName, Size: length}}
+	} else if rawFiles, ok := info["files"].([]interface{}); ok {
+		var offset int64
+		for i, rawFile := range rawFiles {
+			file, ok := rawFile.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			size, _ := file["length"].(int64)
+
+			pathParts := []string{}
+			if rawPath, ok := file["path"].([]interface{}); ok {
+				for _, p := range rawPath {
+					if s, ok := p.(string); ok {
+						pathParts = append(pathParts, s)
+					}
+				}
+			}
+
+			m.Files = append(m.Files, FileInfo{
+				Index:  i,
+				Path:   strings.Join(pathParts, "/"),
+				Offset: offset,
+				Size:   size,
+			})
+			offset += size
+			m.TotalSize += size
+		}
+	}
+
+	if announce != "" {
+		m.Trackers = append(m.Trackers, announce)
+	}
+	for _, tier := range announceList {
+		tierList, ok := tier.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, t := range tierList {
+			if tracker, ok := t.(string); ok {
+				m.Trackers = append(m.Trackers, tracker)
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// decodeBencodeValue decodes a single bencoded value (integer, byte
+// string, list or dictionary) starting at pos, returning the decoded
+// value and the position immediately after it.
+func decodeBencodeValue(data []byte, pos int) (interface{}, int, error) {
+	if pos >= len(data) {
+		return nil, 0, fmt.Errorf("metainfo: unexpected end of data")
+	}
+
+	switch {
+	case data[pos] == 'i':
+		return decodeBencodeInt(data, pos)
+	case data[pos] == 'l':
+		return decodeBencodeList(data, pos)
+	case data[pos] == 'd':
+		return decodeBencodeDict(data, pos)
+	case data[pos] >= '0' && data[pos] <= '9':
+		return decodeBencodeString(data, pos)
+	default:
+		return nil, 0, fmt.Errorf("metainfo: unexpected token %q at offset %d", data[pos], pos)
+	}
+}
+
+func decodeBencodeInt(data []byte, pos int) (int64, int, error) {
+	end := indexByte(data, pos+1, 'e')
+	if end < 0 {
+		return 0, 0, fmt.Errorf("metainfo: unterminated integer")
+	}
+
+	value, err := strconv.ParseInt(string(data[pos+1:end]), 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return value, end + 1, nil
+}
+
+func decodeBencodeString(data []byte, pos int) (string, int, error) {
+	colon := indexByte(data, pos, ':')
+	if colon < 0 {
+		return "", 0, fmt.Errorf("metainfo: malformed string length")
+	}
+
+	length, err := strconv.Atoi(string(data[pos:colon]))
+	if err != nil {
+		return "", 0, err
+	}
+
+	start := colon + 1
+	if start+length > len(data) {
+		return "", 0, fmt.Errorf("metainfo: truncated string")
+	}
+
+	return string(data[start : start+length]), start + length, nil
+}
+
+func decodeBencodeList(data []byte, pos int) ([]interface{}, int, error) {
+	pos++ // skip 'l'
+	result := []interface{}{}
+
+	for pos < len(data) && data[pos] != 'e' {
+		value, newPos, err := decodeBencodeValue(data, pos)
+		if err != nil {
+			return nil, 0, err
+		}
+		result = append(result, value)
+		pos = newPos
+	}
+
+	return result, pos + 1, nil
+}
+
+func decodeBencodeDict(data []byte, pos int) (map[string]interface{}, int, error) {
+	pos++ // skip 'd'
+	result := map[string]interface{}{}
+
+	for pos < len(data) && data[pos] != 'e' {
+		key, newPos, err := decodeBencodeString(data, pos)
+		if err != nil {
+			return nil, 0, err
+		}
+		pos = newPos
+
+		value, newPos, err := decodeBencodeValue(data, pos)
+		if err != nil {
+			return nil, 0, err
+		}
+		result[key] = value
+		pos = newPos
+	}
+
+	return result, pos + 1, nil
+}
+
+func indexByte(data []byte, start int, b byte) int {
+	for i := start; i < len(data); i++ {
+		if data[i] == b {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,87 @@
+// Copyright 2013 Bruno Albuquerque (bga@bug-br.org.br).
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package deluge
+
+import "time"
+
+// options holds the configuration shared by the transports. It is built up
+// by applying the Option values passed to New/NewDaemon.
+type options struct {
+	insecureSkipVerify bool
+	protocolVersion    int
+
+	maxIdleConns     int
+	idleConnTimeout  time.Duration
+	batchConcurrency int
+}
+
+// Option configures a Deluge instance at construction time.
+type Option func(*options)
+
+func defaultOptions() *options {
+	return &options{
+		insecureSkipVerify: true,
+		protocolVersion:    1,
+		maxIdleConns:       100,
+		idleConnTimeout:    90 * time.Second,
+		batchConcurrency:   8,
+	}
+}
+
+// WithInsecureSkipVerify controls whether the daemon transport's TLS
+// connection accepts self-signed certificates (the default used by stock
+// deluged installs). Pass false to require a certificate signed by a known
+// CA.
+func WithInsecureSkipVerify(skip bool) Option {
+	return func(o *options) {
+		o.insecureSkipVerify = skip
+	}
+}
+
+// WithProtocolVersion selects the daemon wire framing to use: 0 for Deluge
+// 1.x (a bare zlib+rencode blob per message) or 1 for Deluge 2.x (a 5 byte
+// header ahead of the blob). It has no effect on the web transport. The
+// default is 1.
+func WithProtocolVersion(version int) Option {
+	return func(o *options) {
+		o.protocolVersion = version
+	}
+}
+
+// WithMaxIdleConns sets the size of the web transport's HTTP connection
+// pool (net/http.Transport's MaxIdleConns/MaxIdleConnsPerHost). The
+// default is 100.
+func WithMaxIdleConns(n int) Option {
+	return func(o *options) {
+		o.maxIdleConns = n
+	}
+}
+
+// WithIdleConnTimeout sets how long an idle connection in the web
+// transport's pool is kept alive before being closed. The default is 90
+// seconds.
+func WithIdleConnTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.idleConnTimeout = d
+	}
+}
+
+// WithBatchConcurrency sets how many calls a Batch runs concurrently. The
+// default is 8.
+func WithBatchConcurrency(n int) Option {
+	return func(o *options) {
+		o.batchConcurrency = n
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2013 Bruno Albuquerque (bga@bug-br.org.br).
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package qbittorrent
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/Hidendra/go-deluge"
+)
+
+func (c *Client) get(path string) ([]byte, error) {
+	req, err := http.NewRequest("GET", c.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.do(req)
+}
+
+func (c *Client) post(path, contentType string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequest("POST", c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	return c.do(req)
+}
+
+func (c *Client) do(req *http.Request) ([]byte, error) {
+	for _, cookie := range c.cookies {
+		req.AddCookie(cookie)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("qbittorrent: request to %s failed with status %d: %s", req.URL.Path, resp.StatusCode, body)
+	}
+
+	return body, nil
+}
+
+// getTorrentsInfo fetches torrents/info filtered to a single hash and maps
+// each entry onto a deluge.TorrentStatus, so GetInfo can share the same
+// typed struct the Deluge implementation uses.
+func (c *Client) getTorrentsInfo(hash string) ([]*deluge.TorrentStatus, error) {
+	body, err := c.get("/api/v2/torrents/info?hashes=" + url.QueryEscape(hash))
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []struct {
+		Name      string  `json:"name"`
+		Hash      string  `json:"hash"`
+		State     string  `json:"state"`
+		Progress  float64 `json:"progress"`
+		Dlspeed   int64   `json:"dlspeed"`
+		Upspeed   int64   `json:"upspeed"`
+		NumSeeds  int     `json:"num_seeds"`
+		NumLeechs int     `json:"num_leechs"`
+		Size      int64   `json:"size"`
+		Completed int64   `json:"completed"`
+		Eta       int64   `json:"eta"`
+		Ratio     float64 `json:"ratio"`
+		SavePath  string  `json:"save_path"`
+		Category  string  `json:"category"`
+		AddedOn   float64 `json:"added_on"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*deluge.TorrentStatus, len(raw))
+	for i, t := range raw {
+		statuses[i] = &deluge.TorrentStatus{
+			Name:  t.Name,
+			Hash:  t.Hash,
+			State: t.State,
+			// qBittorrent reports progress as a 0-1 fraction; TorrentStatus.Progress
+			// is documented as 0-100 to match Deluge, so scale it up.
+			Progress:            t.Progress * 100,
+			DownloadPayloadRate: t.Dlspeed,
+			UploadPayloadRate:   t.Upspeed,
+			NumPeers:            t.NumLeechs,
+			NumSeeds:            t.NumSeeds,
+			TotalSize:           t.Size,
+			TotalDone:           t.Completed,
+			ETA:                 t.Eta,
+			Ratio:               t.Ratio,
+			SavePath:            t.SavePath,
+			Label:               t.Category,
+			TimeAdded:           t.AddedOn,
+		}
+	}
+
+	return statuses, nil
+}
@@ -0,0 +1,226 @@
+// Copyright 2013 Bruno Albuquerque (bga@bug-br.org.br).
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+// Package qbittorrent implements a deluge.TorrentClient against qBittorrent's
+// Web API (https://github.com/qbittorrent/qBittorrent/wiki/WebUI-API-(qBittorrent-4.1)),
+// so downstream applications can switch between Deluge and qBittorrent via
+// configuration alone.
+package qbittorrent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Hidendra/go-deluge"
+)
+
+// Client is an endpoint for qBittorrent Web API requests.
+type Client struct {
+	baseURL string
+
+	client  *http.Client
+	cookies []*http.Cookie
+}
+
+var _ deluge.TorrentClient = (*Client)(nil)
+
+// New instantiates a new Client and authenticates with the qBittorrent Web
+// API at baseURL (e.g. "http://localhost:8080") using username/password.
+func New(baseURL, username, password string) (*Client, error) {
+	c := &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  new(http.Client),
+	}
+
+	form := url.Values{}
+	form.Set("username", username)
+	form.Set("password", password)
+
+	resp, err := c.client.PostForm(c.baseURL+"/api/v2/auth/login", form)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK || string(body) != "Ok." {
+		return nil, fmt.Errorf("qbittorrent: login failed: %s", body)
+	}
+
+	c.cookies = resp.Cookies()
+
+	return c, nil
+}
+
+// AddFromLink implements deluge.TorrentClient by posting to torrents/add
+// with urls set to link. The returned id is the info hash taken from a link
+// that starts with "magnet:?xt=urn:btih:"; for any other link (e.g. a
+// .torrent URL) the returned id is empty.
+func (c *Client) AddFromLink(link string, options map[string]interface{}) (string, error) {
+	form := url.Values{}
+	form.Set("urls", link)
+	applyOptions(form, options)
+
+	if _, err := c.post("/api/v2/torrents/add", "application/x-www-form-urlencoded", strings.NewReader(form.Encode())); err != nil {
+		return "", err
+	}
+
+	return infoHashFromLink(link), nil
+}
+
+// AddFromFile implements deluge.TorrentClient by multipart-uploading the
+// .torrent file read from r to torrents/add. The returned id is the info
+// hash parsed from the file.
+func (c *Client) AddFromFile(name string, r io.Reader, options map[string]interface{}) (string, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	meta, err := deluge.ParseMetainfo(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	part, err := writer.CreateFormFile("torrents", name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", err
+	}
+
+	for key, value := range options {
+		if err := writer.WriteField(key, fmt.Sprintf("%v", value)); err != nil {
+			return "", err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	if _, err := c.post("/api/v2/torrents/add", writer.FormDataContentType(), &body); err != nil {
+		return "", err
+	}
+
+	return meta.InfoHash, nil
+}
+
+// GetInfo implements deluge.TorrentClient via torrents/info?hashes=id.
+func (c *Client) GetInfo(id string) (*deluge.TorrentStatus, error) {
+	torrents, err := c.getTorrentsInfo(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(torrents) == 0 {
+		return nil, fmt.Errorf("qbittorrent: torrent %s not found", id)
+	}
+
+	return torrents[0], nil
+}
+
+// GetFiles implements deluge.TorrentClient via torrents/files?hash=id.
+func (c *Client) GetFiles(id string) ([]deluge.FileInfo, error) {
+	body, err := c.get("/api/v2/torrents/files?hash=" + url.QueryEscape(id))
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []struct {
+		Index int    `json:"index"`
+		Name  string `json:"name"`
+		Size  int64  `json:"size"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+
+	files := make([]deluge.FileInfo, len(raw))
+	for i, f := range raw {
+		files[i] = deluge.FileInfo{Index: f.Index, Path: f.Name, Size: f.Size}
+	}
+
+	return files, nil
+}
+
+// Delete implements deluge.TorrentClient via torrents/delete.
+func (c *Client) Delete(id string, removeData bool) error {
+	form := url.Values{}
+	form.Set("hashes", id)
+	form.Set("deleteFiles", strconv.FormatBool(removeData))
+
+	_, err := c.post("/api/v2/torrents/delete", "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+
+	return err
+}
+
+// Pause implements deluge.TorrentClient via torrents/pause.
+func (c *Client) Pause(id string) error {
+	_, err := c.post("/api/v2/torrents/pause", "application/x-www-form-urlencoded", strings.NewReader("hashes="+url.QueryEscape(id)))
+	return err
+}
+
+// Resume implements deluge.TorrentClient via torrents/resume.
+func (c *Client) Resume(id string) error {
+	_, err := c.post("/api/v2/torrents/resume", "application/x-www-form-urlencoded", strings.NewReader("hashes="+url.QueryEscape(id)))
+	return err
+}
+
+// SetLabel implements deluge.TorrentClient via torrents/setCategory (qBittorrent's
+// equivalent of Deluge's label).
+func (c *Client) SetLabel(id, label string) error {
+	form := url.Values{}
+	form.Set("hashes", id)
+	form.Set("category", label)
+
+	_, err := c.post("/api/v2/torrents/setCategory", "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+
+	return err
+}
+
+func applyOptions(form url.Values, options map[string]interface{}) {
+	for key, value := range options {
+		form.Set(key, fmt.Sprintf("%v", value))
+	}
+}
+
+func infoHashFromLink(link string) string {
+	const prefix = "magnet:?xt=urn:btih:"
+	if !strings.HasPrefix(link, prefix) {
+		return ""
+	}
+
+	rest := link[len(prefix):]
+	if amp := strings.IndexByte(rest, '&'); amp >= 0 {
+		rest = rest[:amp]
+	}
+
+	return rest
+}
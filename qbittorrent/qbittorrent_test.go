@@ -0,0 +1,92 @@
+// Copyright 2013 Bruno Albuquerque (bga@bug-br.org.br).
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package qbittorrent_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Hidendra/go-deluge/qbittorrent"
+	"github.com/Hidendra/go-deluge/torrentclienttest"
+)
+
+const testTorrentID = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+
+// fakeQBittorrentWeb is a minimal stand-in for qBittorrent's Web API, just
+// enough of it for torrentclienttest.Run to exercise *qbittorrent.Client
+// end to end over real HTTP.
+type fakeQBittorrentWeb struct {
+	mux     *http.ServeMux
+	state   string
+	label   string
+	deleted bool
+}
+
+func newFakeQBittorrentWeb() *fakeQBittorrentWeb {
+	f := &fakeQBittorrentWeb{mux: http.NewServeMux(), state: "Downloading"}
+
+	f.mux.HandleFunc("/api/v2/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "Ok.")
+	})
+	f.mux.HandleFunc("/api/v2/torrents/info", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if f.deleted {
+			json.NewEncoder(w).Encode([]map[string]interface{}{})
+			return
+		}
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"hash": testTorrentID, "name": testTorrentID, "state": f.state, "category": f.label},
+		})
+	})
+	f.mux.HandleFunc("/api/v2/torrents/files", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"index": 0, "name": testTorrentID + ".bin", "size": 0},
+		})
+	})
+	f.mux.HandleFunc("/api/v2/torrents/setCategory", func(w http.ResponseWriter, r *http.Request) {
+		f.label = r.FormValue("category")
+	})
+	f.mux.HandleFunc("/api/v2/torrents/pause", func(w http.ResponseWriter, r *http.Request) {
+		f.state = "Paused"
+	})
+	f.mux.HandleFunc("/api/v2/torrents/resume", func(w http.ResponseWriter, r *http.Request) {
+		f.state = "Downloading"
+	})
+	f.mux.HandleFunc("/api/v2/torrents/delete", func(w http.ResponseWriter, r *http.Request) {
+		f.deleted = true
+	})
+
+	return f
+}
+
+func (f *fakeQBittorrentWeb) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mux.ServeHTTP(w, r)
+}
+
+func TestQBittorrentTorrentClientConformance(t *testing.T) {
+	server := httptest.NewServer(newFakeQBittorrentWeb())
+	defer server.Close()
+
+	client, err := qbittorrent.New(server.URL, "user", "pass")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	torrentclienttest.Run(t, client, testTorrentID)
+}
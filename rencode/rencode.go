@@ -0,0 +1,375 @@
+// Copyright 2013 Bruno Albuquerque (bga@bug-br.org.br).
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+// Package rencode implements a minimal subset of the rencode serialization
+// format used by the Deluge daemon RPC protocol
+// (https://github.com/aresch/rencode). It supports the types the daemon
+// protocol actually needs: nil, bool, integers, floats, strings and nested
+// lists/dicts.
+package rencode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+const (
+	chrList    = 59
+	chrDict    = 60
+	chrInt     = 61
+	chrInt1    = 62
+	chrInt2    = 63
+	chrInt4    = 64
+	chrInt8    = 65
+	chrFloat32 = 66
+	chrFloat64 = 44
+	chrTrue    = 67
+	chrFalse   = 68
+	chrNone    = 69
+	chrTerm    = 127
+
+	intPosFixedStart = 0
+	intPosFixedCount = 44
+	intNegFixedStart = 70
+	intNegFixedCount = 32
+	dictFixedStart   = 102
+	dictFixedCount   = 25
+	strFixedStart    = 128
+	strFixedCount    = 64
+	listFixedStart   = 192
+	listFixedCount   = 64
+)
+
+// Encode serializes a value into its rencode representation. Supported
+// values are nil, bool, the various int/float kinds, string, []byte,
+// []string, []interface{} and map[string]interface{} (nested arbitrarily).
+func Encode(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeValue(&buf, value); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func encodeValue(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(chrNone)
+	case bool:
+		if v {
+			buf.WriteByte(chrTrue)
+		} else {
+			buf.WriteByte(chrFalse)
+		}
+	case int:
+		return encodeInt(buf, int64(v))
+	case int64:
+		return encodeInt(buf, v)
+	case uint64:
+		return encodeInt(buf, int64(v))
+	case float32:
+		return encodeFloat32(buf, v)
+	case float64:
+		return encodeFloat64(buf, v)
+	case string:
+		return encodeString(buf, v)
+	case []byte:
+		return encodeString(buf, string(v))
+	case []string:
+		items := make([]interface{}, len(v))
+		for i, s := range v {
+			items[i] = s
+		}
+		return encodeList(buf, items)
+	case []interface{}:
+		return encodeList(buf, v)
+	case map[string]interface{}:
+		return encodeDict(buf, v)
+	default:
+		return fmt.Errorf("rencode: unsupported type %T", value)
+	}
+
+	return nil
+}
+
+func encodeInt(buf *bytes.Buffer, v int64) error {
+	switch {
+	case v >= intPosFixedStart && v < intPosFixedStart+intPosFixedCount:
+		buf.WriteByte(byte(v))
+	case v < 0 && -v <= intNegFixedCount:
+		buf.WriteByte(byte(intNegFixedStart + (-v - 1)))
+	case v >= math.MinInt8 && v <= math.MaxInt8:
+		buf.WriteByte(chrInt1)
+		buf.WriteByte(byte(int8(v)))
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		buf.WriteByte(chrInt2)
+		binary.Write(buf, binary.BigEndian, int16(v))
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		buf.WriteByte(chrInt4)
+		binary.Write(buf, binary.BigEndian, int32(v))
+	default:
+		buf.WriteByte(chrInt8)
+		binary.Write(buf, binary.BigEndian, v)
+	}
+
+	return nil
+}
+
+func encodeFloat32(buf *bytes.Buffer, v float32) error {
+	buf.WriteByte(chrFloat32)
+	return binary.Write(buf, binary.BigEndian, v)
+}
+
+func encodeFloat64(buf *bytes.Buffer, v float64) error {
+	buf.WriteByte(chrFloat64)
+	return binary.Write(buf, binary.BigEndian, v)
+}
+
+func encodeString(buf *bytes.Buffer, v string) error {
+	if len(v) < strFixedCount {
+		buf.WriteByte(byte(strFixedStart + len(v)))
+		buf.WriteString(v)
+		return nil
+	}
+
+	fmt.Fprintf(buf, "%d:", len(v))
+	buf.WriteString(v)
+
+	return nil
+}
+
+func encodeList(buf *bytes.Buffer, v []interface{}) error {
+	if len(v) < listFixedCount {
+		buf.WriteByte(byte(listFixedStart + len(v)))
+		for _, item := range v {
+			if err := encodeValue(buf, item); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	buf.WriteByte(chrList)
+	for _, item := range v {
+		if err := encodeValue(buf, item); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte(chrTerm)
+
+	return nil
+}
+
+func encodeDict(buf *bytes.Buffer, v map[string]interface{}) error {
+	if len(v) < dictFixedCount {
+		buf.WriteByte(byte(dictFixedStart + len(v)))
+		for key, item := range v {
+			if err := encodeString(buf, key); err != nil {
+				return err
+			}
+			if err := encodeValue(buf, item); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	buf.WriteByte(chrDict)
+	for key, item := range v {
+		if err := encodeString(buf, key); err != nil {
+			return err
+		}
+		if err := encodeValue(buf, item); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte(chrTerm)
+
+	return nil
+}
+
+// Decode deserializes a single rencode value from data, returning the value
+// and the number of bytes consumed.
+func Decode(data []byte) (interface{}, int, error) {
+	if len(data) == 0 {
+		return nil, 0, fmt.Errorf("rencode: empty input")
+	}
+
+	return decodeValue(data)
+}
+
+func decodeValue(data []byte) (interface{}, int, error) {
+	b := data[0]
+
+	switch {
+	case b == chrNone:
+		return nil, 1, nil
+	case b == chrTrue:
+		return true, 1, nil
+	case b == chrFalse:
+		return false, 1, nil
+	case b == chrInt1:
+		if len(data) < 2 {
+			return nil, 0, fmt.Errorf("rencode: truncated int1")
+		}
+		return int64(int8(data[1])), 2, nil
+	case b == chrInt2:
+		if len(data) < 3 {
+			return nil, 0, fmt.Errorf("rencode: truncated int2")
+		}
+		return int64(int16(binary.BigEndian.Uint16(data[1:3]))), 3, nil
+	case b == chrInt4:
+		if len(data) < 5 {
+			return nil, 0, fmt.Errorf("rencode: truncated int4")
+		}
+		return int64(int32(binary.BigEndian.Uint32(data[1:5]))), 5, nil
+	case b == chrInt8:
+		if len(data) < 9 {
+			return nil, 0, fmt.Errorf("rencode: truncated int8")
+		}
+		return int64(binary.BigEndian.Uint64(data[1:9])), 9, nil
+	case b == chrFloat32:
+		if len(data) < 5 {
+			return nil, 0, fmt.Errorf("rencode: truncated float32")
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data[1:5]))), 5, nil
+	case b == chrFloat64:
+		if len(data) < 9 {
+			return nil, 0, fmt.Errorf("rencode: truncated float64")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(data[1:9])), 9, nil
+	case b == chrList:
+		return decodeTermList(data[1:], 1)
+	case b == chrDict:
+		return decodeTermDict(data[1:], 1)
+	case b >= intPosFixedStart && b < intPosFixedStart+intPosFixedCount:
+		return int64(b), 1, nil
+	case b >= intNegFixedStart && b < intNegFixedStart+intNegFixedCount:
+		return -int64(b-intNegFixedStart) - 1, 1, nil
+	case b >= strFixedStart && b < strFixedStart+strFixedCount:
+		length := int(b - strFixedStart)
+		if len(data) < 1+length {
+			return nil, 0, fmt.Errorf("rencode: truncated string")
+		}
+		return string(data[1 : 1+length]), 1 + length, nil
+	case b >= listFixedStart && int(b) < listFixedStart+listFixedCount:
+		return decodeFixedList(data[1:], int(b-listFixedStart), 1)
+	case b >= dictFixedStart && b < dictFixedStart+dictFixedCount:
+		return decodeFixedDict(data[1:], int(b-dictFixedStart), 1)
+	case b >= '0' && b <= '9':
+		return decodeLengthPrefixedString(data)
+	default:
+		return nil, 0, fmt.Errorf("rencode: unknown tag byte %d", b)
+	}
+}
+
+func decodeLengthPrefixedString(data []byte) (interface{}, int, error) {
+	sep := bytes.IndexByte(data, ':')
+	if sep < 0 {
+		return nil, 0, fmt.Errorf("rencode: malformed length-prefixed string")
+	}
+
+	length := 0
+	for _, c := range data[:sep] {
+		length = length*10 + int(c-'0')
+	}
+
+	start := sep + 1
+	if len(data) < start+length {
+		return nil, 0, fmt.Errorf("rencode: truncated length-prefixed string")
+	}
+
+	return string(data[start : start+length]), start + length, nil
+}
+
+func decodeFixedList(data []byte, count, consumed int) (interface{}, int, error) {
+	result := make([]interface{}, 0, count)
+	for i := 0; i < count; i++ {
+		value, n, err := decodeValue(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		result = append(result, value)
+		data = data[n:]
+		consumed += n
+	}
+
+	return result, consumed, nil
+}
+
+func decodeTermList(data []byte, consumed int) (interface{}, int, error) {
+	result := []interface{}{}
+	for len(data) > 0 && data[0] != chrTerm {
+		value, n, err := decodeValue(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		result = append(result, value)
+		data = data[n:]
+		consumed += n
+	}
+
+	return result, consumed + 1, nil
+}
+
+func decodeFixedDict(data []byte, count, consumed int) (interface{}, int, error) {
+	result := make(map[string]interface{}, count)
+	for i := 0; i < count; i++ {
+		key, n, err := decodeValue(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		data = data[n:]
+		consumed += n
+
+		value, n, err := decodeValue(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		data = data[n:]
+		consumed += n
+
+		result[fmt.Sprintf("%v", key)] = value
+	}
+
+	return result, consumed, nil
+}
+
+func decodeTermDict(data []byte, consumed int) (interface{}, int, error) {
+	result := map[string]interface{}{}
+	for len(data) > 0 && data[0] != chrTerm {
+		key, n, err := decodeValue(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		data = data[n:]
+		consumed += n
+
+		value, n, err := decodeValue(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		data = data[n:]
+		consumed += n
+
+		result[fmt.Sprintf("%v", key)] = value
+	}
+
+	return result, consumed + 1, nil
+}
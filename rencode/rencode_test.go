@@ -0,0 +1,121 @@
+// Copyright 2013 Bruno Albuquerque (bga@bug-br.org.br).
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package rencode
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func roundTrip(t *testing.T, value interface{}) interface{} {
+	t.Helper()
+
+	encoded, err := Encode(value)
+	if err != nil {
+		t.Fatalf("Encode(%#v): %v", value, err)
+	}
+
+	decoded, n, err := Decode(encoded)
+	if err != nil {
+		t.Fatalf("Decode(%#v): %v", value, err)
+	}
+	if n != len(encoded) {
+		t.Fatalf("Decode(%#v): consumed %d bytes, want %d", value, n, len(encoded))
+	}
+
+	return decoded
+}
+
+func TestEncodeDecodeIntFixedBoundaries(t *testing.T) {
+	for _, v := range []int64{0, 1, intPosFixedCount - 1, intPosFixedCount} {
+		if got := roundTrip(t, v); got != v {
+			t.Errorf("round trip %d: got %v", v, got)
+		}
+	}
+}
+
+func TestEncodeDecodeNegativeInts(t *testing.T) {
+	for _, v := range []int64{-1, -intNegFixedCount, -intNegFixedCount - 1, -300} {
+		if got := roundTrip(t, v); got != v {
+			t.Errorf("round trip %d: got %v", v, got)
+		}
+	}
+}
+
+func TestEncodeDecodeStringFixedBoundary(t *testing.T) {
+	for _, n := range []int{0, 1, strFixedCount - 1, strFixedCount, strFixedCount + 1} {
+		v := strings.Repeat("a", n)
+		got, ok := roundTrip(t, v).(string)
+		if !ok || got != v {
+			t.Errorf("round trip string of length %d: got %q", n, got)
+		}
+	}
+}
+
+func TestEncodeDecodeListFixedBoundary(t *testing.T) {
+	for _, n := range []int{0, 1, listFixedCount - 1, listFixedCount, listFixedCount + 1} {
+		v := make([]interface{}, n)
+		for i := range v {
+			v[i] = int64(i)
+		}
+
+		got, ok := roundTrip(t, v).([]interface{})
+		if !ok || len(got) != n {
+			t.Fatalf("round trip list of length %d: got %#v", n, got)
+		}
+		for i, item := range got {
+			if item != int64(i) {
+				t.Errorf("list[%d] = %v, want %d", i, item, i)
+			}
+		}
+	}
+}
+
+func TestEncodeDecodeNestedListAndDict(t *testing.T) {
+	value := map[string]interface{}{
+		"name": "torrent",
+		"tags": []interface{}{"a", "b"},
+		"meta": map[string]interface{}{
+			"size": int64(1024),
+		},
+	}
+
+	got, ok := roundTrip(t, value).(map[string]interface{})
+	if !ok {
+		t.Fatalf("round trip: got %#v, want map[string]interface{}", got)
+	}
+	if !reflect.DeepEqual(got, value) {
+		t.Errorf("round trip = %#v, want %#v", got, value)
+	}
+}
+
+func TestEncodeDecodeFloat(t *testing.T) {
+	if got := roundTrip(t, float64(3.5)); got != 3.5 {
+		t.Errorf("round trip 3.5: got %v", got)
+	}
+}
+
+func TestEncodeDecodeBoolAndNil(t *testing.T) {
+	if got := roundTrip(t, true); got != true {
+		t.Errorf("round trip true: got %v", got)
+	}
+	if got := roundTrip(t, false); got != false {
+		t.Errorf("round trip false: got %v", got)
+	}
+	if got := roundTrip(t, nil); got != nil {
+		t.Errorf("round trip nil: got %v", got)
+	}
+}
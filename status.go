@@ -0,0 +1,218 @@
+// Copyright 2013 Bruno Albuquerque (bga@bug-br.org.br).
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package deluge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// TorrentStatusKey identifies a single field of core.get_torrent_status /
+// core.get_torrents_status. Its string value is the exact key Deluge
+// expects in the RPC's keys argument.
+type TorrentStatusKey string
+
+// The subset of Deluge's torrent status keys this package knows how to
+// decode into TorrentStatus. Passing keys outside of this list to the
+// *Typed methods is fine (Deluge will still return them) but they will
+// not be reflected on the returned struct.
+const (
+	KeyName                TorrentStatusKey = "name"
+	KeyHash                TorrentStatusKey = "hash"
+	KeyState               TorrentStatusKey = "state"
+	KeyProgress            TorrentStatusKey = "progress"
+	KeyDownloadPayloadRate TorrentStatusKey = "download_payload_rate"
+	KeyUploadPayloadRate   TorrentStatusKey = "upload_payload_rate"
+	KeyNumPeers            TorrentStatusKey = "num_peers"
+	KeyNumSeeds            TorrentStatusKey = "num_seeds"
+	KeyTotalSize           TorrentStatusKey = "total_size"
+	KeyTotalDone           TorrentStatusKey = "total_done"
+	KeyEta                 TorrentStatusKey = "eta"
+	KeyRatio               TorrentStatusKey = "ratio"
+	KeyTrackerStatus       TorrentStatusKey = "tracker_status"
+	KeySavePath            TorrentStatusKey = "save_path"
+	KeyLabel               TorrentStatusKey = "label"
+	KeyTimeAdded           TorrentStatusKey = "time_added"
+	KeyFiles               TorrentStatusKey = "files"
+	KeyFilePriorities      TorrentStatusKey = "file_priorities"
+	KeyFileProgress        TorrentStatusKey = "file_progress"
+	KeyPeers               TorrentStatusKey = "peers"
+	KeyTrackers            TorrentStatusKey = "trackers"
+)
+
+// FileInfo describes a single file inside a torrent, as returned under the
+// "files" status key.
+type FileInfo struct {
+	Index  int    `json:"index,omitempty"`
+	Path   string `json:"path,omitempty"`
+	Offset int64  `json:"offset,omitempty"`
+	Size   int64  `json:"size,omitempty"`
+}
+
+// PeerInfo describes a single connected peer, as returned under the "peers"
+// status key.
+type PeerInfo struct {
+	IP        string  `json:"ip,omitempty"`
+	Client    string  `json:"client,omitempty"`
+	Progress  float64 `json:"progress,omitempty"`
+	DownSpeed int64   `json:"down_speed,omitempty"`
+	UpSpeed   int64   `json:"up_speed,omitempty"`
+	Country   string  `json:"country,omitempty"`
+	Seed      int     `json:"seed,omitempty"`
+}
+
+// TrackerInfo describes a single tracker, as returned under the "trackers"
+// status key.
+type TrackerInfo struct {
+	URL  string `json:"url,omitempty"`
+	Tier int    `json:"tier,omitempty"`
+}
+
+// TorrentStatus is the typed counterpart to the map[string]interface{}
+// returned by CoreGetTorrentStatus/CoreGetTorrentsStatus, covering the
+// commonly used Deluge status fields. Only fields corresponding to keys
+// actually requested (or all of them, if no keys are given) will be
+// populated; the rest are left at their zero value.
+type TorrentStatus struct {
+	Name  string `json:"name,omitempty"`
+	Hash  string `json:"hash,omitempty"`
+	State string `json:"state,omitempty"`
+	// Progress is the torrent's completion percentage, 0-100.
+	Progress            float64       `json:"progress,omitempty"`
+	DownloadPayloadRate int64         `json:"download_payload_rate,omitempty"`
+	UploadPayloadRate   int64         `json:"upload_payload_rate,omitempty"`
+	NumPeers            int           `json:"num_peers,omitempty"`
+	NumSeeds            int           `json:"num_seeds,omitempty"`
+	TotalSize           int64         `json:"total_size,omitempty"`
+	TotalDone           int64         `json:"total_done,omitempty"`
+	ETA                 int64         `json:"eta,omitempty"`
+	Ratio               float64       `json:"ratio,omitempty"`
+	TrackerStatus       string        `json:"tracker_status,omitempty"`
+	SavePath            string        `json:"save_path,omitempty"`
+	Label               string        `json:"label,omitempty"`
+	TimeAdded           float64       `json:"time_added,omitempty"`
+	Files               []FileInfo    `json:"files,omitempty"`
+	FilePriorities      []int         `json:"file_priorities,omitempty"`
+	FileProgress        []float64     `json:"file_progress,omitempty"`
+	Peers               []PeerInfo    `json:"peers,omitempty"`
+	Trackers            []TrackerInfo `json:"trackers,omitempty"`
+}
+
+// Filter narrows down the torrents core.get_torrents_status returns,
+// mirroring the filter dict Deluge's core itself supports.
+type Filter struct {
+	State       string `json:"state,omitempty"`
+	Label       string `json:"label,omitempty"`
+	TrackerHost string `json:"tracker_host,omitempty"`
+	Keyword     string `json:"keyword,omitempty"`
+}
+
+// toMap turns a Filter into the plain dict Deluge's RPC expects, omitting
+// any fields left at their zero value.
+func (f Filter) toMap() map[string]interface{} {
+	m := map[string]interface{}{}
+
+	if f.State != "" {
+		m["state"] = f.State
+	}
+	if f.Label != "" {
+		m["label"] = f.Label
+	}
+	if f.TrackerHost != "" {
+		m["tracker_host"] = f.TrackerHost
+	}
+	if f.Keyword != "" {
+		m["keyword"] = f.Keyword
+	}
+
+	return m
+}
+
+func keyStrings(keys []TorrentStatusKey) []string {
+	result := make([]string, len(keys))
+	for i, k := range keys {
+		result[i] = string(k)
+	}
+
+	return result
+}
+
+// CoreGetTorrentStatusTyped wraps core.get_torrent_status the same way
+// CoreGetTorrentStatus does, but decodes the result directly into a
+// TorrentStatus instead of leaving callers to type-assert a
+// map[string]interface{}. keys selects which fields to request from
+// Deluge; pass none to request all of them.
+func (d *Deluge) CoreGetTorrentStatusTyped(id string, keys ...TorrentStatusKey) (*TorrentStatus, error) {
+	return d.CoreGetTorrentStatusTypedContext(context.Background(), id, keys...)
+}
+
+// CoreGetTorrentStatusTypedContext is CoreGetTorrentStatusTyped with a
+// caller-supplied context for cancellation.
+func (d *Deluge) CoreGetTorrentStatusTypedContext(ctx context.Context, id string, keys ...TorrentStatusKey) (*TorrentStatus, error) {
+	raw, err := d.transport.Call(ctx, "core.get_torrent_status", []interface{}{id, keyStrings(keys)})
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeTorrentStatus(raw)
+}
+
+// CoreGetTorrentsStatusTyped wraps core.get_torrents_status, decoding the
+// result into a map of torrent id to TorrentStatus. filter narrows down
+// which torrents are returned and keys selects which fields to populate;
+// pass no keys to request all of them.
+func (d *Deluge) CoreGetTorrentsStatusTyped(filter Filter, keys ...TorrentStatusKey) (map[string]*TorrentStatus, error) {
+	return d.CoreGetTorrentsStatusTypedContext(context.Background(), filter, keys...)
+}
+
+// CoreGetTorrentsStatusTypedContext is CoreGetTorrentsStatusTyped with a
+// caller-supplied context for cancellation.
+func (d *Deluge) CoreGetTorrentsStatusTypedContext(ctx context.Context, filter Filter, keys ...TorrentStatusKey) (map[string]*TorrentStatus, error) {
+	raw, err := d.transport.Call(ctx, "core.get_torrents_status", []interface{}{filter.toMap(), keyStrings(keys)})
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("core.get_torrents_status: unexpected result type %T", raw)
+	}
+
+	result := make(map[string]*TorrentStatus, len(m))
+	for id, value := range m {
+		status, err := decodeTorrentStatus(value)
+		if err != nil {
+			return nil, err
+		}
+		result[id] = status
+	}
+
+	return result, nil
+}
+
+func decodeTorrentStatus(raw interface{}) (*TorrentStatus, error) {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &TorrentStatus{}
+	if err := json.Unmarshal(data, status); err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
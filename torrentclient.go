@@ -0,0 +1,112 @@
+// Copyright 2013 Bruno Albuquerque (bga@bug-br.org.br).
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package deluge
+
+import (
+	"context"
+	"io"
+	"strings"
+)
+
+// TorrentClient is a backend-agnostic interface over the handful of
+// operations most downstream applications actually need from a torrent
+// daemon, so they can be written against either this package or against
+// another backend (e.g. qbittorrent) interchangeably. *Deluge implements
+// it; see the sibling qbittorrent package for a second implementation.
+type TorrentClient interface {
+	// AddFromLink submits a magnet link or .torrent URL and returns the
+	// torrent's id (info hash).
+	AddFromLink(link string, options map[string]interface{}) (string, error)
+
+	// AddFromFile reads a .torrent file from r, submits it under name and
+	// returns the torrent's id (info hash).
+	AddFromFile(name string, r io.Reader, options map[string]interface{}) (string, error)
+
+	// GetInfo returns the status of a single torrent.
+	GetInfo(id string) (*TorrentStatus, error)
+
+	// GetFiles returns the list of files contained in a torrent.
+	GetFiles(id string) ([]FileInfo, error)
+
+	// Delete removes a torrent, optionally along with its downloaded data.
+	Delete(id string, removeData bool) error
+
+	// Pause pauses a torrent.
+	Pause(id string) error
+
+	// Resume resumes a paused torrent.
+	Resume(id string) error
+
+	// SetLabel assigns a label to a torrent.
+	SetLabel(id, label string) error
+}
+
+var _ TorrentClient = (*Deluge)(nil)
+
+// AddFromLink implements TorrentClient. A magnet: link is submitted via
+// core.add_torrent_magnet; anything else is treated as a .torrent URL and
+// submitted via core.add_torrent_url.
+func (d *Deluge) AddFromLink(link string, options map[string]interface{}) (string, error) {
+	if strings.HasPrefix(link, "magnet:") {
+		return d.CoreAddTorrentMagnet(link, options)
+	}
+
+	return d.CoreAddTorrentUrl(link, options)
+}
+
+// AddFromFile implements TorrentClient.
+func (d *Deluge) AddFromFile(name string, r io.Reader, options map[string]interface{}) (string, error) {
+	return d.CoreAddTorrentFromReader(name, r, options)
+}
+
+// GetInfo implements TorrentClient.
+func (d *Deluge) GetInfo(id string) (*TorrentStatus, error) {
+	return d.CoreGetTorrentStatusTyped(id)
+}
+
+// GetFiles implements TorrentClient.
+func (d *Deluge) GetFiles(id string) ([]FileInfo, error) {
+	status, err := d.CoreGetTorrentStatusTyped(id, KeyFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	return status.Files, nil
+}
+
+// Delete implements TorrentClient.
+func (d *Deluge) Delete(id string, removeData bool) error {
+	_, err := d.CoreRemoveTorrent(id, removeData)
+	return err
+}
+
+// Pause wraps the core.pause_torrent RPC call for a single torrent id.
+func (d *Deluge) Pause(id string) error {
+	_, err := d.transport.Call(context.Background(), "core.pause_torrent", []interface{}{[]interface{}{id}})
+	return err
+}
+
+// Resume wraps the core.resume_torrent RPC call for a single torrent id.
+func (d *Deluge) Resume(id string) error {
+	_, err := d.transport.Call(context.Background(), "core.resume_torrent", []interface{}{[]interface{}{id}})
+	return err
+}
+
+// SetLabel wraps the label.set_torrent RPC call provided by Deluge's
+// built-in Label plugin.
+func (d *Deluge) SetLabel(id, label string) error {
+	_, err := d.transport.Call(context.Background(), "label.set_torrent", []interface{}{id, label})
+	return err
+}
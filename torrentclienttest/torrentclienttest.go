@@ -0,0 +1,94 @@
+// Copyright 2013 Bruno Albuquerque (bga@bug-br.org.br).
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+// Package torrentclienttest is a conformance suite shared by the
+// deluge.TorrentClient implementations in this module (the Deluge client
+// itself and the qbittorrent client). Each implementation's own tests call
+// Run against a client wired up to a fake backend, so the two stay
+// behaviorally interchangeable as either one evolves.
+package torrentclienttest
+
+import (
+	"strings"
+
+	"github.com/Hidendra/go-deluge"
+)
+
+// TB is the subset of testing.TB the suite needs, so callers can pass
+// either a *testing.T or a *testing.B.
+type TB interface {
+	Helper()
+	Fatalf(format string, args ...interface{})
+}
+
+// testLabel is the label Run assigns via SetLabel; it then checks the
+// label sticks by re-fetching the torrent's info.
+const testLabel = "test-label"
+
+// Run exercises GetInfo, GetFiles, SetLabel, Pause, Resume and Delete
+// against client, which is expected to be wired up to a fake or sandboxed
+// backend seeded with a single torrent identified by id; AddFromLink and
+// AddFromFile are not exercised. Rather than just checking each
+// call returns no error, it re-fetches the torrent's info after each
+// mutation to confirm the backend's state actually changed, so a no-op
+// implementation can't pass.
+func Run(t TB, client deluge.TorrentClient, id string) {
+	t.Helper()
+
+	info, err := client.GetInfo(id)
+	if err != nil {
+		t.Fatalf("GetInfo(%q): %v", id, err)
+	}
+	if info.Hash != "" && !strings.EqualFold(info.Hash, id) {
+		t.Fatalf("GetInfo(%q): got hash %q", id, info.Hash)
+	}
+
+	if _, err := client.GetFiles(id); err != nil {
+		t.Fatalf("GetFiles(%q): %v", id, err)
+	}
+
+	if err := client.SetLabel(id, testLabel); err != nil {
+		t.Fatalf("SetLabel(%q): %v", id, err)
+	}
+	if info, err := client.GetInfo(id); err != nil {
+		t.Fatalf("GetInfo(%q) after SetLabel: %v", id, err)
+	} else if info.Label != testLabel {
+		t.Fatalf("GetInfo(%q) after SetLabel: got label %q, want %q", id, info.Label, testLabel)
+	}
+
+	if err := client.Pause(id); err != nil {
+		t.Fatalf("Pause(%q): %v", id, err)
+	}
+	if info, err := client.GetInfo(id); err != nil {
+		t.Fatalf("GetInfo(%q) after Pause: %v", id, err)
+	} else if !strings.Contains(strings.ToLower(info.State), "pause") {
+		t.Fatalf("GetInfo(%q) after Pause: got state %q, want it to indicate the torrent is paused", id, info.State)
+	}
+
+	if err := client.Resume(id); err != nil {
+		t.Fatalf("Resume(%q): %v", id, err)
+	}
+	if info, err := client.GetInfo(id); err != nil {
+		t.Fatalf("GetInfo(%q) after Resume: %v", id, err)
+	} else if strings.Contains(strings.ToLower(info.State), "pause") {
+		t.Fatalf("GetInfo(%q) after Resume: got state %q, want it to no longer indicate paused", id, info.State)
+	}
+
+	if err := client.Delete(id, false); err != nil {
+		t.Fatalf("Delete(%q): %v", id, err)
+	}
+	if _, err := client.GetInfo(id); err == nil {
+		t.Fatalf("GetInfo(%q) after Delete: expected an error, got none", id)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2013 Bruno Albuquerque (bga@bug-br.org.br).
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package deluge
+
+import (
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// CoreAddTorrentFileFromPath reads the .torrent file at path and submits it
+// via CoreAddTorrentFile, handling the base64 encoding internally.
+func (d *Deluge) CoreAddTorrentFileFromPath(path string, options map[string]interface{}) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	return d.CoreAddTorrentFromReader(filepath.Base(path), f, options)
+}
+
+// CoreAddTorrentFromReader reads a .torrent file from r and submits it via
+// CoreAddTorrentFile under name, handling the base64 encoding internally.
+func (d *Deluge) CoreAddTorrentFromReader(name string, r io.Reader, options map[string]interface{}) (string, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	return d.CoreAddTorrentFile(name, base64.StdEncoding.EncodeToString(data), options)
+}
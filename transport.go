@@ -0,0 +1,217 @@
+// Copyright 2013 Bruno Albuquerque (bga@bug-br.org.br).
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package deluge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Transport performs a single RPC call against a Deluge endpoint and returns
+// the decoded "result" value. Deluge talks to either deluge-web (over JSON
+// inside plain HTTP) or deluged itself (over a native rencode protocol);
+// WebJSONTransport and DaemonTransport implement the two respectively.
+type Transport interface {
+	// Call invokes method with params and returns the RPC result. It
+	// returns early with ctx.Err() if ctx is canceled before the call
+	// completes.
+	Call(ctx context.Context, method string, params []interface{}) (interface{}, error)
+
+	// Close releases any resources (connections, etc.) held by the
+	// transport.
+	Close() error
+}
+
+// WebJSONTransport talks to the deluge-web JSON-RPC endpoint over HTTP, the
+// same protocol the original client in this package used.
+type WebJSONTransport struct {
+	url      string
+	password string
+
+	client *http.Client
+
+	cookieMu sync.Mutex
+	cookies  []*http.Cookie
+
+	id uint64
+
+	debugMu  sync.Mutex
+	debugW   io.Writer
+	recorded []Interaction
+}
+
+// NewWebJSONTransport connects to the deluge-web JSON endpoint at url and
+// authenticates with password. opts controls the underlying HTTP
+// transport's connection pool.
+func NewWebJSONTransport(url, password string, opts *options) (*WebJSONTransport, error) {
+	t := &WebJSONTransport{
+		url:      url,
+		password: password,
+		client: &http.Client{
+			Transport: &http.Transport{
+				MaxIdleConns:        opts.maxIdleConns,
+				MaxIdleConnsPerHost: opts.maxIdleConns,
+				IdleConnTimeout:     opts.idleConnTimeout,
+			},
+		},
+	}
+
+	response, err := t.sendJsonRequest(context.Background(), "auth.login", []interface{}{password})
+	if err != nil {
+		return nil, err
+	}
+
+	if response["result"] != true {
+		return nil, fmt.Errorf("authetication failed")
+	}
+
+	return t, nil
+}
+
+// Call implements Transport.
+func (t *WebJSONTransport) Call(ctx context.Context, method string, params []interface{}) (interface{}, error) {
+	response, err := t.sendJsonRequest(ctx, method, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return response["result"], nil
+}
+
+// Close implements Transport. The web transport has no persistent
+// connection to tear down.
+func (t *WebJSONTransport) Close() error {
+	return nil
+}
+
+var _ debugger = (*WebJSONTransport)(nil)
+
+func (t *WebJSONTransport) setDebugWriter(w io.Writer) {
+	t.debugMu.Lock()
+	defer t.debugMu.Unlock()
+
+	t.debugW = w
+}
+
+func (t *WebJSONTransport) interactions() []Interaction {
+	t.debugMu.Lock()
+	defer t.debugMu.Unlock()
+
+	result := make([]Interaction, len(t.recorded))
+	copy(result, t.recorded)
+
+	return result
+}
+
+// record stores interaction in the ring buffer and, if a debug writer is
+// set, appends it as a newline-delimited JSON record.
+func (t *WebJSONTransport) record(interaction Interaction) {
+	t.debugMu.Lock()
+	defer t.debugMu.Unlock()
+
+	t.recorded = append(t.recorded, interaction)
+	if len(t.recorded) > maxInteractions {
+		t.recorded = t.recorded[len(t.recorded)-maxInteractions:]
+	}
+
+	if t.debugW == nil {
+		return
+	}
+
+	if data, err := json.Marshal(interaction); err == nil {
+		t.debugW.Write(append(data, '\n'))
+	}
+}
+
+func (t *WebJSONTransport) sendJsonRequest(ctx context.Context, method string, params []interface{}) (map[string]interface{}, error) {
+	requestID := atomic.AddUint64(&(t.id), 1)
+	interaction := Interaction{Method: method, RequestID: requestID, Timestamp: time.Now()}
+
+	result, err := t.doSendJsonRequest(ctx, method, requestID, params, &interaction)
+	if err != nil {
+		interaction.Err = err.Error()
+	}
+	t.record(interaction)
+
+	return result, err
+}
+
+func (t *WebJSONTransport) doSendJsonRequest(ctx context.Context, method string, requestID uint64, params []interface{}, interaction *Interaction) (map[string]interface{}, error) {
+	data, err := json.Marshal(map[string]interface{}{
+		"method": method,
+		"id":     requestID,
+		"params": params,
+	})
+	if err != nil {
+		return nil, err
+	}
+	interaction.Request = data
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	t.cookieMu.Lock()
+	for _, cookie := range t.cookies {
+		req.AddCookie(cookie)
+	}
+	t.cookieMu.Unlock()
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	interaction.StatusCode = resp.StatusCode
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("received non-ok status to http request : %d", resp.StatusCode)
+	}
+
+	t.cookieMu.Lock()
+	t.cookies = resp.Cookies()
+	t.cookieMu.Unlock()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	interaction.Response = body
+
+	result := make(map[string]interface{})
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	if result["error"] != nil {
+		return nil, fmt.Errorf("json error : %v", result["error"])
+	}
+
+	return result, err
+}